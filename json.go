@@ -0,0 +1,138 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// jsonFields lists every field NewJSONHandler knows how to emit. entry is
+// a map, so encoding/json always renders keys alphabetically regardless of
+// this slice's order - it exists only to define the default field set and
+// what resolveJSONFields subtracts exclusions from.
+var jsonFields = []string{
+	"remote_addr",
+	"user",
+	"time",
+	"method",
+	"uri",
+	"proto",
+	"status",
+	"size",
+	"duration_ms",
+	"referer",
+	"user_agent",
+	"request_id",
+}
+
+// defaultRequestIDHeader is the header NewJSONHandler reads the
+// "request_id" field from when requestIDHeader is empty.
+const defaultRequestIDHeader = "X-Request-ID"
+
+var jsonBufferPool = sync.Pool{
+	New: func() interface{} { return new(bytes.Buffer) },
+}
+
+// resolveJSONFields turns the fields argument of NewJSONHandler into the
+// concrete set of jsonFields to emit. A nil or empty fields yields every
+// built-in field. Names prefixed with "-" (e.g. "-user_agent") are treated
+// as exclusions from the default set; any other non-empty fields is taken
+// as the literal set to include, replacing the default. Mixing the two
+// forms in one call isn't supported - an include wins over any excludes
+// present in the same slice.
+func resolveJSONFields(fields []string) []string {
+	if len(fields) == 0 {
+		return jsonFields
+	}
+
+	excluded := make(map[string]bool, len(fields))
+	for _, field := range fields {
+		if !strings.HasPrefix(field, "-") {
+			return fields
+		}
+		excluded[strings.TrimPrefix(field, "-")] = true
+	}
+
+	include := make([]string, 0, len(jsonFields))
+	for _, field := range jsonFields {
+		if !excluded[field] {
+			include = append(include, field)
+		}
+	}
+
+	return include
+}
+
+// NewJSONHandler returns a http.Handler that wraps h, emitting one JSON
+// object per request to writer via CustomHandler.
+//
+// fields selects which of the built-in fields (see jsonFields) are
+// included; a nil or empty slice includes all of them. Prefixing every
+// entry with "-" (e.g. []string{"-user_agent"}) instead excludes those
+// fields from the default set. requestIDHeader names the header the
+// "request_id" field is read from, defaulting to X-Request-ID. extra adds
+// caller-defined fields - e.g. trace IDs or tenant tags - computed from
+// the completed request. redactor, if non-nil, masks sensitive query
+// parameters and headers before they reach the JSON output.
+func NewJSONHandler(h http.Handler, writer io.Writer, fields []string, requestIDHeader string, extra map[string]func(*http.Request, LogFormatterParams) interface{}, stats MetricsSink, redactor *Redactor) http.Handler {
+	if requestIDHeader == "" {
+		requestIDHeader = defaultRequestIDHeader
+	}
+
+	include := resolveJSONFields(fields)
+
+	formatter := func(w io.Writer, p LogFormatterParams) {
+		entry := make(map[string]interface{}, len(include)+len(extra))
+
+		for _, field := range include {
+			switch field {
+			case "remote_addr":
+				entry[field] = extractRemoteIP(p.Request)
+			case "user":
+				entry[field] = extractUsername(p.Request)
+			case "time":
+				entry[field] = p.TimeStamp.Format(timeFormat)
+			case "method":
+				entry[field] = p.Request.Method
+			case "uri":
+				entry[field] = p.URL.RequestURI()
+			case "proto":
+				entry[field] = p.Request.Proto
+			case "status":
+				entry[field] = p.StatusCode
+			case "size":
+				entry[field] = p.Size
+			case "duration_ms":
+				entry[field] = float64(p.Duration) / float64(time.Millisecond)
+			case "referer":
+				entry[field] = p.Header.Get("Referer")
+			case "user_agent":
+				entry[field] = p.Header.Get("User-Agent")
+			case "request_id":
+				entry[field] = p.Header.Get(requestIDHeader)
+			}
+		}
+
+		for name, fn := range extra {
+			entry[name] = fn(p.Request, p)
+		}
+
+		buf := jsonBufferPool.Get().(*bytes.Buffer)
+		buf.Reset()
+		defer jsonBufferPool.Put(buf)
+
+		if err := json.NewEncoder(buf).Encode(entry); err != nil {
+			return
+		}
+
+		// A single Write call so concurrent requests can't interleave
+		// partial lines.
+		w.Write(buf.Bytes())
+	}
+
+	return CustomHandler(h, writer, formatter, stats, redactor)
+}
@@ -5,12 +5,11 @@ import (
 	"io"
 	"net"
 	"net/http"
+	"net/url"
 	"os"
 	"strconv"
 	"strings"
 	"time"
-
-	"github.com/DataDog/datadog-go/statsd"
 )
 
 // Type represents logger's type
@@ -18,7 +17,7 @@ type Type int
 
 const (
 	// Version is this package's version
-	Version = "0.3.0"
+	Version = "0.4.0"
 
 	// CombineLoggerType is the standard Apache combined log output
 	//
@@ -53,10 +52,54 @@ const (
 	//
 	// :method :url :status :res[content-length] - :response-time ms
 	TinyLoggerType
+	// JSONLoggerType emits one JSON object per request. It has no fixed
+	// field set, so it isn't usable through Handler directly - build it
+	// with NewJSONHandler instead.
+	JSONLoggerType
 
 	timeFormat = "02/Jan/2006:15:04:05 -0700"
 )
 
+// LogFormatterParams bundles everything a LogFormatter needs in order to
+// render a log line for a completed request.
+type LogFormatterParams struct {
+	// Request is the original *http.Request, unmodified.
+	Request *http.Request
+
+	// URL is a snapshot of req.URL taken before ServeHTTP runs, so that
+	// mutations made by downstream handlers don't corrupt the logged URL.
+	// If a Redactor was configured, sensitive query parameters are already
+	// masked here.
+	URL url.URL
+
+	// Header is a copy of req.Header taken before ServeHTTP runs, with
+	// any Redactor-configured headers masked. Formatters that log headers
+	// should use this instead of Request.Header, which is left untouched
+	// so downstream handlers still see real credentials. When no Redactor
+	// headers are configured, Header is req.Header itself rather than a
+	// copy, so it reflects changes made by downstream handlers.
+	Header http.Header
+
+	// TimeStamp is when the request started being handled.
+	TimeStamp time.Time
+
+	// StatusCode is the response status. It is http.StatusOK if the
+	// handler wrote a body without calling WriteHeader, and 0 if the
+	// handler wrote neither a header nor a body.
+	StatusCode int
+
+	// Size is the number of bytes written to the response body.
+	Size int
+
+	// Duration is how long ServeHTTP took to return.
+	Duration time.Duration
+}
+
+// LogFormatter renders a LogFormatterParams to w. Implementations should
+// perform a single Write call so concurrent requests don't interleave
+// partial lines.
+type LogFormatter func(io.Writer, LogFormatterParams)
+
 type responseLogger struct {
 	rw     http.ResponseWriter
 	start  time.Time
@@ -86,6 +129,13 @@ func (rl *responseLogger) WriteHeader(status int) {
 	rl.rw.WriteHeader(status)
 }
 
+// Flush is promoted onto every wrapResponseWriter combination in wrap.go,
+// unlike Hijack/Push/CloseNotify/ReadFrom, so a wrapped ResponseWriter
+// always satisfies http.Flusher even if rw doesn't. That's a deliberate
+// exception to the gating those four get: Flush (unlike the other four)
+// already no-ops safely via the ok check below when rw isn't a Flusher,
+// so callers that probe for it and call it can't misbehave the way they
+// would calling Hijack/Push/CloseNotify/ReadFrom on an unsupported writer.
 func (rl *responseLogger) Flush() {
 	f, ok := rl.rw.(http.Flusher)
 
@@ -95,19 +145,33 @@ func (rl *responseLogger) Flush() {
 }
 
 type loggerHandler struct {
-	h          http.Handler
-	formatType Type
-	writer     io.Writer
-	logFn      func(io.Writer, *responseLogger, *http.Request)
-	stats      *statsd.Client
+	h         http.Handler
+	writer    io.Writer
+	formatter LogFormatter
+	stats     MetricsSink
+	redactor  *Redactor
 }
 
 func (rh loggerHandler) ServeHTTP(res http.ResponseWriter, req *http.Request) {
-	rl := &responseLogger{rw: res, start: time.Now()}
+	start := time.Now()
+	snapshotURL := rh.redactor.redactURL(*req.URL)
+	snapshotHeader := rh.redactor.redactHeader(req.Header)
+
+	wrapped, rl := wrapResponseWriter(res, start)
+
+	rh.h.ServeHTTP(wrapped, req)
 
-	rh.h.ServeHTTP(rl, req)
+	params := LogFormatterParams{
+		Request:    req,
+		URL:        snapshotURL,
+		Header:     snapshotHeader,
+		TimeStamp:  start,
+		StatusCode: rl.status,
+		Size:       rl.size,
+		Duration:   time.Now().Sub(start),
+	}
 
-	rh.logFn(rh.writer, rl, req)
+	rh.formatter(rh.writer, params)
 
 	if rh.stats != nil {
 		tags := []string{
@@ -115,9 +179,9 @@ func (rh loggerHandler) ServeHTTP(res http.ResponseWriter, req *http.Request) {
 			"method:" + req.Method,
 		}
 
-		rh.stats.Incr("http.response", tags, 1)
-		rh.stats.Gauge("http.size", float64(rl.size), tags, 1)
-		rh.stats.Timing("http.response", time.Now().Sub(rl.start), tags, 1)
+		rh.stats.Incr("http.response", tags)
+		rh.stats.Gauge("http.size", float64(rl.size), tags)
+		rh.stats.Timing("http.response", params.Duration, tags)
 	}
 }
 
@@ -138,95 +202,127 @@ func extractRemoteIP(req *http.Request) string {
 	return host
 }
 
-func parseResponseTime(start time.Time) string {
-	return fmt.Sprintf("%.3f ms", time.Now().Sub(start).Seconds()/1e6)
+func parseResponseTime(d time.Duration) string {
+	return fmt.Sprintf("%.3f ms", float64(d)/float64(time.Millisecond))
 }
 
 // DefaultHandler returns a http.Handler that wraps h by using
 // Apache combined log output and print to os.Stdout
 func DefaultHandler(h http.Handler) http.Handler {
-	return Handler(h, os.Stdout, CombineLoggerType, nil)
+	return Handler(h, os.Stdout, CombineLoggerType, nil, nil)
 }
 
 // Handler returns a http.Hanlder that wraps h by using t type log output
-// and print to writer
-func Handler(h http.Handler, writer io.Writer, t Type, stats *statsd.Client) http.Handler {
+// and print to writer. redactor, if non-nil, masks sensitive query
+// parameters and headers before they reach the log.
+//
+// JSONLoggerType has no fixed field set, so it can't be used here - build
+// it with NewJSONHandler instead. Passing JSONLoggerType logs a one-line
+// diagnostic to writer on every request rather than using it.
+func Handler(h http.Handler, writer io.Writer, t Type, stats MetricsSink, redactor *Redactor) http.Handler {
+	return CustomHandler(h, writer, formatterForType(t), stats, redactor)
+}
+
+// CustomHandler returns a http.Handler that wraps h, rendering each
+// completed request through f instead of one of the built-in Type
+// formats. This mirrors gorilla/handlers' CustomLoggingHandler and lets
+// callers plug in JSON, logfmt, or Sentry-breadcrumb emitters without
+// forking the package. redactor, if non-nil, masks sensitive query
+// parameters and headers before they reach f.
+func CustomHandler(h http.Handler, writer io.Writer, f LogFormatter, stats MetricsSink, redactor *Redactor) http.Handler {
 	return loggerHandler{
-		h:      h,
-		writer: writer,
-		logFn:  logFnForType(t),
-		stats:  stats,
+		h:         h,
+		writer:    writer,
+		formatter: f,
+		stats:     stats,
+		redactor:  redactor,
 	}
 }
 
-func logFnForType(t Type) func(io.Writer, *responseLogger, *http.Request) {
+// formatterForType builds the LogFormatter for each built-in Type. Note
+// that these formatters render the request target via p.URL.RequestURI()
+// - the reconstructed path+query of the snapshotted, possibly redacted
+// url.URL - rather than the pre-0.4.0 behavior of logging req.RequestURI
+// verbatim. That's required so Redactor can mask query parameters before
+// they're logged, but it also means any existing Handler caller now logs
+// a reconstructed target instead of the client's literal one: most
+// requests are unaffected, but authority-form (CONNECT host:port) and
+// absolute-form proxy targets will render differently.
+func formatterForType(t Type) LogFormatter {
 	switch t {
 	case CombineLoggerType:
-		return func(w io.Writer, rl *responseLogger, req *http.Request) {
+		return func(w io.Writer, p LogFormatterParams) {
 			fmt.Fprintln(w, strings.Join([]string{
-				extractRemoteIP(req),
+				extractRemoteIP(p.Request),
 				"-",
-				extractUsername(req),
-				"[" + rl.start.Format(timeFormat) + "]",
-				`"` + req.Method,
-				req.RequestURI,
-				req.Proto + `"`,
-				strconv.Itoa(rl.status),
-				strconv.Itoa(rl.size),
-				`"` + req.Referer() + `"`,
-				`"` + req.UserAgent() + `"`,
+				extractUsername(p.Request),
+				"[" + p.TimeStamp.Format(timeFormat) + "]",
+				`"` + p.Request.Method,
+				p.URL.RequestURI(),
+				p.Request.Proto + `"`,
+				strconv.Itoa(p.StatusCode),
+				strconv.Itoa(p.Size),
+				`"` + p.Header.Get("Referer") + `"`,
+				`"` + p.Header.Get("User-Agent") + `"`,
 			}, " "))
 		}
 	case CommonLoggerType:
-		return func(w io.Writer, rl *responseLogger, req *http.Request) {
+		return func(w io.Writer, p LogFormatterParams) {
 			fmt.Fprintln(w, strings.Join([]string{
-				extractRemoteIP(req),
+				extractRemoteIP(p.Request),
 				"-",
-				extractUsername(req),
-				"[" + rl.start.Format(timeFormat) + "]",
-				`"` + req.Method,
-				req.RequestURI,
-				req.Proto + `"`,
-				strconv.Itoa(rl.status),
-				strconv.Itoa(rl.size),
+				extractUsername(p.Request),
+				"[" + p.TimeStamp.Format(timeFormat) + "]",
+				`"` + p.Request.Method,
+				p.URL.RequestURI(),
+				p.Request.Proto + `"`,
+				strconv.Itoa(p.StatusCode),
+				strconv.Itoa(p.Size),
 			}, " "))
 		}
 	case DevLoggerType:
-		return func(w io.Writer, rl *responseLogger, req *http.Request) {
+		return func(w io.Writer, p LogFormatterParams) {
 			fmt.Fprintln(w, strings.Join([]string{
-				req.Method,
-				req.RequestURI,
-				strconv.Itoa(rl.status),
-				parseResponseTime(rl.start),
+				p.Request.Method,
+				p.URL.RequestURI(),
+				strconv.Itoa(p.StatusCode),
+				parseResponseTime(p.Duration),
 				"-",
-				strconv.Itoa(rl.size),
+				strconv.Itoa(p.Size),
 			}, " "))
 		}
 	case ShortLoggerType:
-		return func(w io.Writer, rl *responseLogger, req *http.Request) {
+		return func(w io.Writer, p LogFormatterParams) {
 			fmt.Fprintln(w, strings.Join([]string{
-				extractRemoteIP(req),
-				extractUsername(req),
-				req.Method,
-				req.RequestURI,
-				req.Proto,
-				strconv.Itoa(rl.status),
-				strconv.Itoa(rl.size),
+				extractRemoteIP(p.Request),
+				extractUsername(p.Request),
+				p.Request.Method,
+				p.URL.RequestURI(),
+				p.Request.Proto,
+				strconv.Itoa(p.StatusCode),
+				strconv.Itoa(p.Size),
 				"-",
-				parseResponseTime(rl.start),
+				parseResponseTime(p.Duration),
 			}, " "))
 		}
 	case TinyLoggerType:
-		return func(w io.Writer, rl *responseLogger, req *http.Request) {
+		return func(w io.Writer, p LogFormatterParams) {
 			fmt.Fprintln(w, strings.Join([]string{
-				req.Method,
-				req.RequestURI,
-				strconv.Itoa(rl.status),
-				strconv.Itoa(rl.size),
+				p.Request.Method,
+				p.URL.RequestURI(),
+				strconv.Itoa(p.StatusCode),
+				strconv.Itoa(p.Size),
 				"-",
-				parseResponseTime(rl.start),
+				parseResponseTime(p.Duration),
 			}, " "))
 		}
+	case JSONLoggerType:
+		// JSONLoggerType has no fixed field set, so Handler can't build a
+		// formatter for it - degrade to a diagnostic line instead of
+		// panicking on every request that reaches a misconfigured Handler.
+		return func(w io.Writer, p LogFormatterParams) {
+			fmt.Fprintln(w, "logger: JSONLoggerType has no fixed field set, use NewJSONHandler instead of Handler")
+		}
 	}
 	panic("Should never get here.")
 }
@@ -0,0 +1,18 @@
+package logger
+
+import "time"
+
+// MetricsSink is the interface loggerHandler uses to emit per-request
+// metrics, so the core package doesn't hard-depend on any one metrics
+// client. See the logger/statsd, logger/prometheus, and logger/otel
+// sub-packages for ready-made adapters.
+type MetricsSink interface {
+	// Incr increments a counter by 1.
+	Incr(name string, tags []string)
+
+	// Gauge records an instantaneous value.
+	Gauge(name string, value float64, tags []string)
+
+	// Timing records a duration, typically as a histogram or timer.
+	Timing(name string, d time.Duration, tags []string)
+}
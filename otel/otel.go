@@ -0,0 +1,54 @@
+// Package otel adapts OpenTelemetry metric instruments to
+// logger.MetricsSink.
+package otel
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+// Sink adapts OpenTelemetry instruments to logger.MetricsSink.
+type Sink struct {
+	Counter         metric.Int64Counter
+	GaugeInstrument metric.Float64Gauge
+	Histogram       metric.Float64Histogram
+}
+
+func attributesFromTags(tags []string) []attribute.KeyValue {
+	attrs := make([]attribute.KeyValue, 0, len(tags))
+
+	for _, tag := range tags {
+		parts := strings.SplitN(tag, ":", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		attrs = append(attrs, attribute.String(parts[0], parts[1]))
+	}
+
+	return attrs
+}
+
+// Incr implements logger.MetricsSink.
+func (s *Sink) Incr(name string, tags []string) {
+	if s.Counter != nil {
+		s.Counter.Add(context.Background(), 1, metric.WithAttributes(attributesFromTags(tags)...))
+	}
+}
+
+// Gauge implements logger.MetricsSink.
+func (s *Sink) Gauge(name string, value float64, tags []string) {
+	if s.GaugeInstrument != nil {
+		s.GaugeInstrument.Record(context.Background(), value, metric.WithAttributes(attributesFromTags(tags)...))
+	}
+}
+
+// Timing implements logger.MetricsSink.
+func (s *Sink) Timing(name string, d time.Duration, tags []string) {
+	if s.Histogram != nil {
+		s.Histogram.Record(context.Background(), d.Seconds(), metric.WithAttributes(attributesFromTags(tags)...))
+	}
+}
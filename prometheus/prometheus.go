@@ -0,0 +1,61 @@
+// Package prometheus adapts Prometheus client_golang metric vectors to
+// logger.MetricsSink.
+package prometheus
+
+import (
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Sink adapts Prometheus metric vectors to logger.MetricsSink. Tags passed
+// to Incr/Gauge/Timing are "key:value" pairs, as produced by the logger
+// package's loggerHandler; Labels lists, in the order the vectors expect
+// them, which keys to pull out as label values.
+type Sink struct {
+	Labels    []string
+	Counter   *prometheus.CounterVec
+	GaugeVec  *prometheus.GaugeVec
+	Histogram *prometheus.HistogramVec
+}
+
+func (s *Sink) labelValues(tags []string) []string {
+	m := make(map[string]string, len(tags))
+
+	for _, tag := range tags {
+		parts := strings.SplitN(tag, ":", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		m[parts[0]] = parts[1]
+	}
+
+	values := make([]string, len(s.Labels))
+	for i, name := range s.Labels {
+		values[i] = m[name]
+	}
+
+	return values
+}
+
+// Incr implements logger.MetricsSink.
+func (s *Sink) Incr(name string, tags []string) {
+	if s.Counter != nil {
+		s.Counter.WithLabelValues(s.labelValues(tags)...).Inc()
+	}
+}
+
+// Gauge implements logger.MetricsSink.
+func (s *Sink) Gauge(name string, value float64, tags []string) {
+	if s.GaugeVec != nil {
+		s.GaugeVec.WithLabelValues(s.labelValues(tags)...).Set(value)
+	}
+}
+
+// Timing implements logger.MetricsSink.
+func (s *Sink) Timing(name string, d time.Duration, tags []string) {
+	if s.Histogram != nil {
+		s.Histogram.WithLabelValues(s.labelValues(tags)...).Observe(d.Seconds())
+	}
+}
@@ -0,0 +1,107 @@
+package logger
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"runtime/debug"
+)
+
+// RecoveryOption configures RecoveryHandler.
+type RecoveryOption func(*recoveryHandler)
+
+// WithStackTrace controls whether a recovered panic's stack trace is
+// written alongside the panic message. Defaults to true.
+func WithStackTrace(enabled bool) RecoveryOption {
+	return func(rh *recoveryHandler) { rh.stackTrace = enabled }
+}
+
+// WithLogger sets where the panic message (and stack trace, if enabled) is
+// written. Defaults to os.Stderr.
+func WithLogger(w io.Writer) RecoveryOption {
+	return func(rh *recoveryHandler) { rh.writer = w }
+}
+
+// WithPanicHandler registers a callback invoked with the recovered value
+// and its stack trace, e.g. to forward the panic to
+// sentry.CurrentHub().Recover(...).
+func WithPanicHandler(f func(interface{}, []byte)) RecoveryOption {
+	return func(rh *recoveryHandler) { rh.panicHandler = f }
+}
+
+// WithMetrics sets the MetricsSink an "http.panic" counter is incremented on
+// when RecoveryHandler recovers a panic.
+func WithMetrics(stats MetricsSink) RecoveryOption {
+	return func(rh *recoveryHandler) { rh.stats = stats }
+}
+
+type recoveryHandler struct {
+	h            http.Handler
+	writer       io.Writer
+	stackTrace   bool
+	panicHandler func(interface{}, []byte)
+	stats        MetricsSink
+}
+
+// statusGetter is implemented by responseLogger and its wrap.go variants,
+// letting RecoveryHandler tell whether a response has already started.
+type statusGetter interface {
+	Status() int
+}
+
+// RecoveryHandler returns a http.Handler that recovers from panics raised
+// by h, writes a 500 response if headers haven't been sent yet, and logs
+// the panic. Wrap it inside a Handler/CustomHandler chain (i.e.
+// Handler(RecoveryHandler(mux), ...)) so the access log still records the
+// resulting 500 status and response size instead of the request
+// disappearing silently.
+func RecoveryHandler(h http.Handler, opts ...RecoveryOption) http.Handler {
+	rh := &recoveryHandler{
+		h:          h,
+		writer:     os.Stderr,
+		stackTrace: true,
+	}
+
+	for _, opt := range opts {
+		opt(rh)
+	}
+
+	return rh
+}
+
+func (rh *recoveryHandler) ServeHTTP(res http.ResponseWriter, req *http.Request) {
+	defer func() {
+		rec := recover()
+		if rec == nil {
+			return
+		}
+
+		stack := debug.Stack()
+
+		status := 0
+		if sg, ok := res.(statusGetter); ok {
+			status = sg.Status()
+		}
+
+		if status == 0 {
+			res.WriteHeader(http.StatusInternalServerError)
+		}
+
+		fmt.Fprintf(rh.writer, "panic: %v\n", rec)
+
+		if rh.stackTrace {
+			rh.writer.Write(stack)
+		}
+
+		if rh.stats != nil {
+			rh.stats.Incr("http.panic", []string{"method:" + req.Method})
+		}
+
+		if rh.panicHandler != nil {
+			rh.panicHandler(rec, stack)
+		}
+	}()
+
+	rh.h.ServeHTTP(res, req)
+}
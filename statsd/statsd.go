@@ -0,0 +1,35 @@
+// Package statsd adapts a DataDog statsd client to logger.MetricsSink, so
+// existing users of the logger package keep working after its core module
+// dropped the hard dependency on github.com/DataDog/datadog-go.
+package statsd
+
+import (
+	"time"
+
+	"github.com/DataDog/datadog-go/statsd"
+)
+
+// Sink adapts *statsd.Client to logger.MetricsSink.
+type Sink struct {
+	client *statsd.Client
+}
+
+// New returns a logger.MetricsSink backed by client.
+func New(client *statsd.Client) *Sink {
+	return &Sink{client: client}
+}
+
+// Incr implements logger.MetricsSink.
+func (s *Sink) Incr(name string, tags []string) {
+	s.client.Incr(name, tags, 1)
+}
+
+// Gauge implements logger.MetricsSink.
+func (s *Sink) Gauge(name string, value float64, tags []string) {
+	s.client.Gauge(name, value, tags, 1)
+}
+
+// Timing implements logger.MetricsSink.
+func (s *Sink) Timing(name string, d time.Duration, tags []string) {
+	s.client.Timing(name, d, tags, 1)
+}
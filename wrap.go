@@ -0,0 +1,215 @@
+package logger
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"net/http"
+	"time"
+)
+
+// Status returns the response status captured so far, or 0 if the handler
+// hasn't written one yet.
+func (rl *responseLogger) Status() int {
+	return rl.status
+}
+
+// wrapResponseWriter wraps rw for status/size accounting while preserving
+// whichever of http.Hijacker, http.Pusher, http.CloseNotifier, and
+// io.ReaderFrom rw itself implements. Handlers that type-assert the
+// ResponseWriter they're given (e.g. to upgrade a WebSocket via Hijack, or
+// to push via HTTP/2) keep working through the wrapper, and status/size are
+// still captured when a handler calls Hijack or ReadFrom directly instead
+// of Write.
+//
+// This is a fixed 16-combination switch, one per subset of the four
+// optional interfaces rw may implement, rather than a single wrapper type
+// that declares all four unconditionally - doing the latter would make
+// e.g. a non-Hijacker ResponseWriter satisfy http.Hijacker, which breaks
+// callers that probe for it with a type assertion.
+//
+// http.Flusher is deliberately not one of the four gated interfaces - see
+// the comment on responseLogger.Flush in logger.go for why.
+func wrapResponseWriter(rw http.ResponseWriter, start time.Time) (http.ResponseWriter, *responseLogger) {
+	rl := &responseLogger{rw: rw, start: start}
+
+	_, hijacker := rw.(http.Hijacker)
+	_, pusher := rw.(http.Pusher)
+	_, closeNotifier := rw.(http.CloseNotifier)
+	_, readerFrom := rw.(io.ReaderFrom)
+
+	switch {
+	case hijacker && pusher && closeNotifier && readerFrom:
+		return &rlHijackerPusherCloseNotifierReaderFrom{rl}, rl
+	case hijacker && pusher && closeNotifier:
+		return &rlHijackerPusherCloseNotifier{rl}, rl
+	case hijacker && pusher && readerFrom:
+		return &rlHijackerPusherReaderFrom{rl}, rl
+	case hijacker && closeNotifier && readerFrom:
+		return &rlHijackerCloseNotifierReaderFrom{rl}, rl
+	case pusher && closeNotifier && readerFrom:
+		return &rlPusherCloseNotifierReaderFrom{rl}, rl
+	case hijacker && pusher:
+		return &rlHijackerPusher{rl}, rl
+	case hijacker && closeNotifier:
+		return &rlHijackerCloseNotifier{rl}, rl
+	case hijacker && readerFrom:
+		return &rlHijackerReaderFrom{rl}, rl
+	case pusher && closeNotifier:
+		return &rlPusherCloseNotifier{rl}, rl
+	case pusher && readerFrom:
+		return &rlPusherReaderFrom{rl}, rl
+	case closeNotifier && readerFrom:
+		return &rlCloseNotifierReaderFrom{rl}, rl
+	case hijacker:
+		return &rlHijacker{rl}, rl
+	case pusher:
+		return &rlPusher{rl}, rl
+	case closeNotifier:
+		return &rlCloseNotifier{rl}, rl
+	case readerFrom:
+		return &rlReaderFrom{rl}, rl
+	default:
+		return rl, rl
+	}
+}
+
+func (rl *responseLogger) hijack() (net.Conn, *bufio.ReadWriter, error) {
+	conn, rw, err := rl.rw.(http.Hijacker).Hijack()
+	if err == nil && rl.status == 0 {
+		rl.status = http.StatusSwitchingProtocols
+	}
+
+	return conn, rw, err
+}
+
+func (rl *responseLogger) push(target string, opts *http.PushOptions) error {
+	return rl.rw.(http.Pusher).Push(target, opts)
+}
+
+func (rl *responseLogger) closeNotify() <-chan bool {
+	return rl.rw.(http.CloseNotifier).CloseNotify()
+}
+
+func (rl *responseLogger) readFrom(src io.Reader) (int64, error) {
+	if rl.status == 0 {
+		rl.status = http.StatusOK
+	}
+
+	n, err := rl.rw.(io.ReaderFrom).ReadFrom(src)
+
+	rl.size += int(n)
+
+	return n, err
+}
+
+type rlHijacker struct{ *responseLogger }
+
+func (rl *rlHijacker) Hijack() (net.Conn, *bufio.ReadWriter, error) { return rl.hijack() }
+
+type rlPusher struct{ *responseLogger }
+
+func (rl *rlPusher) Push(target string, opts *http.PushOptions) error { return rl.push(target, opts) }
+
+type rlCloseNotifier struct{ *responseLogger }
+
+func (rl *rlCloseNotifier) CloseNotify() <-chan bool { return rl.closeNotify() }
+
+type rlReaderFrom struct{ *responseLogger }
+
+func (rl *rlReaderFrom) ReadFrom(src io.Reader) (int64, error) { return rl.readFrom(src) }
+
+type rlHijackerPusher struct{ *responseLogger }
+
+func (rl *rlHijackerPusher) Hijack() (net.Conn, *bufio.ReadWriter, error) { return rl.hijack() }
+func (rl *rlHijackerPusher) Push(target string, opts *http.PushOptions) error {
+	return rl.push(target, opts)
+}
+
+type rlHijackerCloseNotifier struct{ *responseLogger }
+
+func (rl *rlHijackerCloseNotifier) Hijack() (net.Conn, *bufio.ReadWriter, error) { return rl.hijack() }
+func (rl *rlHijackerCloseNotifier) CloseNotify() <-chan bool                    { return rl.closeNotify() }
+
+type rlHijackerReaderFrom struct{ *responseLogger }
+
+func (rl *rlHijackerReaderFrom) Hijack() (net.Conn, *bufio.ReadWriter, error) { return rl.hijack() }
+func (rl *rlHijackerReaderFrom) ReadFrom(src io.Reader) (int64, error)        { return rl.readFrom(src) }
+
+type rlPusherCloseNotifier struct{ *responseLogger }
+
+func (rl *rlPusherCloseNotifier) Push(target string, opts *http.PushOptions) error {
+	return rl.push(target, opts)
+}
+func (rl *rlPusherCloseNotifier) CloseNotify() <-chan bool { return rl.closeNotify() }
+
+type rlPusherReaderFrom struct{ *responseLogger }
+
+func (rl *rlPusherReaderFrom) Push(target string, opts *http.PushOptions) error {
+	return rl.push(target, opts)
+}
+func (rl *rlPusherReaderFrom) ReadFrom(src io.Reader) (int64, error) { return rl.readFrom(src) }
+
+type rlCloseNotifierReaderFrom struct{ *responseLogger }
+
+func (rl *rlCloseNotifierReaderFrom) CloseNotify() <-chan bool { return rl.closeNotify() }
+func (rl *rlCloseNotifierReaderFrom) ReadFrom(src io.Reader) (int64, error) {
+	return rl.readFrom(src)
+}
+
+type rlHijackerPusherCloseNotifier struct{ *responseLogger }
+
+func (rl *rlHijackerPusherCloseNotifier) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return rl.hijack()
+}
+func (rl *rlHijackerPusherCloseNotifier) Push(target string, opts *http.PushOptions) error {
+	return rl.push(target, opts)
+}
+func (rl *rlHijackerPusherCloseNotifier) CloseNotify() <-chan bool { return rl.closeNotify() }
+
+type rlHijackerPusherReaderFrom struct{ *responseLogger }
+
+func (rl *rlHijackerPusherReaderFrom) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return rl.hijack()
+}
+func (rl *rlHijackerPusherReaderFrom) Push(target string, opts *http.PushOptions) error {
+	return rl.push(target, opts)
+}
+func (rl *rlHijackerPusherReaderFrom) ReadFrom(src io.Reader) (int64, error) {
+	return rl.readFrom(src)
+}
+
+type rlHijackerCloseNotifierReaderFrom struct{ *responseLogger }
+
+func (rl *rlHijackerCloseNotifierReaderFrom) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return rl.hijack()
+}
+func (rl *rlHijackerCloseNotifierReaderFrom) CloseNotify() <-chan bool { return rl.closeNotify() }
+func (rl *rlHijackerCloseNotifierReaderFrom) ReadFrom(src io.Reader) (int64, error) {
+	return rl.readFrom(src)
+}
+
+type rlPusherCloseNotifierReaderFrom struct{ *responseLogger }
+
+func (rl *rlPusherCloseNotifierReaderFrom) Push(target string, opts *http.PushOptions) error {
+	return rl.push(target, opts)
+}
+func (rl *rlPusherCloseNotifierReaderFrom) CloseNotify() <-chan bool { return rl.closeNotify() }
+func (rl *rlPusherCloseNotifierReaderFrom) ReadFrom(src io.Reader) (int64, error) {
+	return rl.readFrom(src)
+}
+
+type rlHijackerPusherCloseNotifierReaderFrom struct{ *responseLogger }
+
+func (rl *rlHijackerPusherCloseNotifierReaderFrom) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return rl.hijack()
+}
+func (rl *rlHijackerPusherCloseNotifierReaderFrom) Push(target string, opts *http.PushOptions) error {
+	return rl.push(target, opts)
+}
+func (rl *rlHijackerPusherCloseNotifierReaderFrom) CloseNotify() <-chan bool {
+	return rl.closeNotify()
+}
+func (rl *rlHijackerPusherCloseNotifierReaderFrom) ReadFrom(src io.Reader) (int64, error) {
+	return rl.readFrom(src)
+}